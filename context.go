@@ -0,0 +1,121 @@
+package gnow
+
+import (
+	"context"
+)
+
+// ConnectContext is like Connect, but returns ctx.Err() if ctx is
+// done before the connection to the server is established. The dial
+// itself is not interrupted: it keeps running in the background, and
+// a connection it still establishes is closed.
+// The server, port, usr and pwd arguments are as in Connect.
+func ConnectContext(ctx context.Context, server, port, usr, pwd string) (*Connection, error) {
+	type result struct {
+		c   *Connection
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		c, err := Connect(server, port, usr, pwd)
+		ch <- result{c, err}
+	}()
+	select {
+	case <-ctx.Done():
+		go func() {
+			r := <-ch
+			if r.c != nil {
+				r.c.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	case r := <-ch:
+		return r.c, r.err
+	}
+}
+
+// ExecuteContext is like Execute, but returns ctx.Err() as soon as
+// ctx is done, without waiting for the server to reply.
+//
+// nowdb exposes no documented, thread-safe way to interrupt a
+// blocking nowdb_exec_statement from another goroutine, so
+// cancellation here does not abort the statement on the server: it
+// keeps running to completion on its own, unobserved, on c. Once it
+// finally completes, the now-abandoned connection is closed to
+// release its resources; Connection.Close is safe to call
+// concurrently (e.g. from a caller's own deferred Close racing this
+// cleanup), but nothing else is, since it would still race the
+// in-flight statement for the same C handle. The connection must
+// therefore not be used again, beyond Close, after ExecuteContext
+// returns ctx.Err(); establish a new one instead.
+func (c *Connection) ExecuteContext(ctx context.Context, stmt string) (*Result, error) {
+	if ctx.Done() == nil {
+		return c.Execute(stmt)
+	}
+
+	type outcome struct {
+		r   *Result
+		err error
+	}
+	ch := make(chan outcome, 1)
+	go func() {
+		c.mu.Lock()
+		r, err := c.Execute(stmt)
+		c.mu.Unlock()
+		ch <- outcome{r, err}
+	}()
+
+	select {
+	case o := <-ch:
+		return o.r, o.err
+	case <-ctx.Done():
+		go func() {
+			o := <-ch
+			if o.r != nil {
+				o.r.Destroy()
+			}
+			c.Close()
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// FetchContext is like Fetch, but returns ctx.Err() as soon as ctx
+// is done, without waiting for the next row.
+//
+// nowdb exposes no documented, thread-safe way to interrupt a
+// blocking nowdb_cursor_fetch from another goroutine, so
+// cancellation here does not abort the fetch on the server: it
+// keeps running to completion on its own, unobserved, on c. Once it
+// finally completes, the now-abandoned cursor is closed to release
+// its resources; Cursor.Close is safe to call concurrently (e.g.
+// from a caller's own deferred Close racing this cleanup), but
+// nothing else is, since it would still race the in-flight fetch
+// for the same C handle. The cursor must therefore not be used
+// again, beyond Close, after FetchContext returns ctx.Err(); it is,
+// in effect, already closed.
+func (c *Cursor) FetchContext(ctx context.Context) (*Row, error) {
+	if ctx.Done() == nil {
+		return c.Fetch()
+	}
+
+	type outcome struct {
+		row *Row
+		err error
+	}
+	ch := make(chan outcome, 1)
+	go func() {
+		row, err := c.Fetch()
+		ch <- outcome{row, err}
+	}()
+
+	select {
+	case o := <-ch:
+		return o.row, o.err
+	case <-ctx.Done():
+		go func() {
+			<-ch
+			c.Close()
+		}()
+		return nil, ctx.Err()
+	}
+}
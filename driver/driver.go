@@ -0,0 +1,385 @@
+// Package driver implements the database/sql/driver interface for nowdb.
+//
+// It registers a driver named "nowdb", so that a *sql.DB can be obtained
+// with the standard library alone:
+//
+// 	db, err := sql.Open("nowdb", "server=localhost port=55505 user=usr password=pwd database=mydb")
+// 	if err != nil {
+// 		// error handling
+// 	}
+// 	defer db.Close()
+// 	rows, err := db.Query("select count(*) from mytable")
+// 	if err != nil {
+// 		// error handling
+// 	}
+// 	defer rows.Close()
+//
+// All the machinery built on top of database/sql (connection pooling,
+// QueryContext, sql.Tx, sql.Rows.Scan, ...) works transparently; gnow's
+// own Connection/Execute/Open/Fetch API remains available for callers
+// who want direct access to the C client.
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toschoo/gonow"
+)
+
+func init() {
+	sql.Register("nowdb", &Driver{})
+}
+
+// Driver implements driver.Driver and driver.DriverContext.
+type Driver struct{}
+
+// Open opens a new connection using the given DSN.
+// Most callers should prefer sql.Open, which calls this for them.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+// OpenConnector parses dsn and returns a Connector that dials
+// lazily and honors context cancellation on Connect.
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Connector{cfg: cfg, drv: d}, nil
+}
+
+// config holds the pieces a DSN may set.
+type config struct {
+	server   string
+	port     string
+	user     string
+	password string
+	database string
+}
+
+// parseDSN parses a DSN of the form
+//
+// 	server=<host> port=<port> user=<usr> password=<pwd> database=<db>
+//
+// server and port are mandatory; user, password and database are optional.
+func parseDSN(dsn string) (*config, error) {
+	cfg := new(config)
+	for _, field := range strings.Fields(dsn) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("nowdb: invalid dsn field %q", field)
+		}
+		switch kv[0] {
+		case "server", "host":
+			cfg.server = kv[1]
+		case "port":
+			cfg.port = kv[1]
+		case "user":
+			cfg.user = kv[1]
+		case "password":
+			cfg.password = kv[1]
+		case "database":
+			cfg.database = kv[1]
+		default:
+			return nil, fmt.Errorf("nowdb: unknown dsn field %q", kv[0])
+		}
+	}
+	if cfg.server == "" || cfg.port == "" {
+		return nil, fmt.Errorf("nowdb: dsn must set server and port")
+	}
+	return cfg, nil
+}
+
+// Connector implements driver.Connector.
+type Connector struct {
+	cfg *config
+	drv *Driver
+}
+
+// Connect dials the server, honoring ctx's deadline and cancellation.
+// If cfg.database is set, it is selected with Use before the
+// connection is handed back.
+func (n *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	c, err := gnow.ConnectContext(ctx, n.cfg.server, n.cfg.port, n.cfg.user, n.cfg.password)
+	if err != nil {
+		return nil, err
+	}
+	if n.cfg.database != "" {
+		if err := c.Use(n.cfg.database); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return &Conn{c: c}, nil
+}
+
+// Driver returns the Driver that created this Connector.
+func (n *Connector) Driver() driver.Driver {
+	return n.drv
+}
+
+// Conn implements driver.Conn and driver.Pinger. It does not
+// implement driver.QueryerContext, driver.ExecerContext or
+// driver.ConnBeginTx, so database/sql falls back to the
+// Prepare/PrepareContext path for every query and exec, and Begin
+// always fails since nowdb has no transaction statements.
+type Conn struct {
+	c *gnow.Connection
+}
+
+// Prepare returns a statement bound to query.
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{s: s}, nil
+}
+
+// PrepareContext is like Prepare but aborts if ctx is done before
+// the statement is ready.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Prepare(query)
+}
+
+// Close closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.c.Close()
+}
+
+// Begin is not supported; nowdb has no transaction statements.
+func (c *Conn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("nowdb: transactions are not supported")
+}
+
+// Ping reports whether the connection is still usable, transparently
+// reconnecting it if not.
+func (c *Conn) Ping(ctx context.Context) error {
+	return c.c.Ping()
+}
+
+// Stmt implements driver.Stmt. Parameter binding is delegated to
+// gnow.Stmt, which substitutes nowdb literals for the statement's
+// '?' placeholders.
+type Stmt struct {
+	s *gnow.Stmt
+}
+
+// Close is a no-op; Stmt holds no server-side resources.
+func (s *Stmt) Close() error {
+	return nil
+}
+
+// NumInput returns the number of '?' placeholders found in the
+// statement when it was prepared.
+func (s *Stmt) NumInput() int {
+	return s.s.NumInput()
+}
+
+// Exec runs the statement and discards any resulting rows.
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	r, err := s.s.Execute(valuesToArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+	r.Destroy()
+	return driver.ResultNoRows, nil
+}
+
+// Query runs the statement and returns its result set.
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	r, err := s.s.Execute(valuesToArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+	if r.TellType() != gnow.CursorT {
+		r.Destroy()
+		return &Rows{}, nil
+	}
+	cur, err := r.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{cur: cur}, nil
+}
+
+// valuesToArgs widens driver.Value, which is restricted to a closed
+// set of types, to the wider interface{} that gnow.Stmt.Execute
+// accepts.
+func valuesToArgs(args []driver.Value) []interface{} {
+	vs := make([]interface{}, len(args))
+	for i, a := range args {
+		vs[i] = a
+	}
+	return vs
+}
+
+// Rows implements driver.Rows, driver.RowsColumnTypeScanType and
+// driver.RowsColumnTypeDatabaseTypeName.
+type Rows struct {
+	cur     *gnow.Cursor
+	columns []string
+	row     *gnow.Row
+}
+
+// Columns returns the column labels that gnow.Row.Name gives
+// ("col0", "col1", ...), one per field of the first row.
+//
+// nowdb's wire protocol exposes column metadata only through a
+// fetched row, so Columns has a hard limitation: for a result set
+// with zero rows there is no row to read names from, and Columns
+// reports none, rather than the true column count. database/sql
+// then believes the query has zero columns, so sql.Rows.Scan on
+// such an (empty) result set reports the wrong expected argument
+// count. Affected callers should check sql.Rows.Next before relying
+// on Columns, as the database/sql documentation already recommends.
+func (r *Rows) Columns() []string {
+	if r.columns != nil {
+		return r.columns
+	}
+	if err := r.fetchFirst(); err != nil {
+		return []string{}
+	}
+	return r.columns
+}
+
+func (r *Rows) fetchFirst() error {
+	if r.row != nil || r.cur == nil {
+		return nil
+	}
+	row, err := r.cur.Fetch()
+	if err != nil {
+		return err
+	}
+	r.row = row
+	r.columns = make([]string, row.Count())
+	for i := range r.columns {
+		r.columns[i] = row.Name(i)
+	}
+	return nil
+}
+
+// Close releases the underlying cursor.
+func (r *Rows) Close() error {
+	if r.cur != nil {
+		r.cur.Close()
+		r.cur = nil
+	}
+	return nil
+}
+
+// Next fills dest with the next row's values, translating gnow's EOF
+// into io.EOF as database/sql expects.
+func (r *Rows) Next(dest []driver.Value) error {
+	if r.cur == nil {
+		return io.EOF
+	}
+	row := r.row
+	if row == nil {
+		var err error
+		row, err = r.cur.Fetch()
+		if err != nil {
+			if err == gnow.EOF {
+				return io.EOF
+			}
+			return err
+		}
+	} else {
+		r.row = nil
+	}
+	for i := range dest {
+		t, v := row.Field(i)
+		switch t {
+		case gnow.NOTHING:
+			dest[i] = nil
+		case gnow.DATE, gnow.TIME:
+			dest[i] = gnow.Now2Go(v.(int64))
+		case gnow.UINT:
+			dest[i] = uintValue(v.(uint64))
+		default:
+			dest[i] = v
+		}
+	}
+	return nil
+}
+
+// uintValue narrows a nowdb UINT field to a type permitted in
+// driver.Value, which has no unsigned integer of its own. Values
+// that fit into an int64 are returned as one, since database/sql's
+// convertAssign already knows how to widen an int64 back into a
+// *uint64 (or other numeric) scan destination; values that don't
+// fit are rendered as a decimal string instead.
+func uintValue(u uint64) driver.Value {
+	if u <= math.MaxInt64 {
+		return int64(u)
+	}
+	return strconv.FormatUint(u, 10)
+}
+
+// ColumnTypeScanType reports the Go type database/sql should allocate
+// to scan into when no destination is given.
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	if err := r.fetchFirst(); err != nil {
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+	t, _ := r.row.Field(index)
+	switch t {
+	case gnow.TEXT:
+		return reflect.TypeOf("")
+	case gnow.DATE, gnow.TIME:
+		return reflect.TypeOf(time.Time{})
+	case gnow.INT:
+		return reflect.TypeOf(int64(0))
+	case gnow.UINT:
+		return reflect.TypeOf(uint64(0))
+	case gnow.FLOAT:
+		return reflect.TypeOf(float64(0))
+	case gnow.BOOL:
+		return reflect.TypeOf(false)
+	default:
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+}
+
+// ColumnTypeDatabaseTypeName reports nowdb's own type name for the column.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	if err := r.fetchFirst(); err != nil {
+		return ""
+	}
+	t, _ := r.row.Field(index)
+	switch t {
+	case gnow.TEXT:
+		return "TEXT"
+	case gnow.DATE:
+		return "DATE"
+	case gnow.TIME:
+		return "TIME"
+	case gnow.INT:
+		return "INT"
+	case gnow.UINT:
+		return "UINT"
+	case gnow.FLOAT:
+		return "FLOAT"
+	case gnow.BOOL:
+		return "BOOL"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,189 @@
+package gnow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CSVOptions configures Cursor.WriteCSV.
+type CSVOptions struct {
+	// Delimiter separates fields. The zero value defaults to ','.
+	Delimiter rune
+	// Header, if true, writes a header row of column names
+	// before the first data row.
+	Header bool
+	// AlwaysQuote, if true, quotes every field regardless of its
+	// content. By default, a field is quoted only if it contains
+	// the delimiter, a double quote, or a newline.
+	AlwaysQuote bool
+}
+
+// WriteJSON streams the remaining rows of the cursor to w as a
+// top-level JSON array of objects keyed by the column labels that
+// Row.Name returns ("col0", "col1", ...), one row at
+// a time, without buffering the whole result set in memory. TIME
+// and DATE values are rendered as RFC3339 strings via Now2Go;
+// NOTHING fields are emitted as null. The cursor is exhausted, but
+// not closed, by this call.
+func (c *Cursor) WriteJSON(w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	for {
+		row, err := c.Fetch()
+		if err != nil {
+			if err == EOF {
+				break
+			}
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		if err := writeJSONRow(w, row); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+func writeJSONRow(w io.Writer, row *Row) error {
+	if _, err := io.WriteString(w, "{"); err != nil {
+		return err
+	}
+	for i := 0; i < row.Count(); i++ {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		key, err := json.Marshal(row.Name(i))
+		if err != nil {
+			return err
+		}
+		val, err := json.Marshal(jsonValue(row, i))
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s:%s", key, val); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+func jsonValue(row *Row, idx int) interface{} {
+	t, v := row.Field(idx)
+	switch t {
+	case NOTHING:
+		return nil
+	case TIME, DATE:
+		return Now2Go(v.(int64)).Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// WriteCSV streams the remaining rows of the cursor to w as CSV,
+// as configured by opts, one row at a time, without buffering the
+// whole result set in memory. TIME and DATE values are rendered as
+// RFC3339 strings via Now2Go; NOTHING fields are emitted as the
+// empty field. The cursor is exhausted, but not closed, by this
+// call.
+//
+// The header holds the column labels that Row.Name returns, one per
+// field of the first row; nowdb's wire protocol exposes the number
+// of columns only through a fetched row, so if opts.Header is set
+// but the cursor has no rows at all, no header is written; WriteCSV
+// then writes nothing.
+func (c *Cursor) WriteCSV(w io.Writer, opts CSVOptions) error {
+	delim := opts.Delimiter
+	if delim == 0 {
+		delim = ','
+	}
+
+	row, err := c.Fetch()
+	if err != nil {
+		if err == EOF {
+			return nil
+		}
+		return err
+	}
+
+	if opts.Header {
+		names := make([]string, row.Count())
+		for i := range names {
+			names[i] = row.Name(i)
+		}
+		if err := writeCSVRecord(w, names, delim, opts.AlwaysQuote); err != nil {
+			return err
+		}
+	}
+
+	for {
+		fields := make([]string, row.Count())
+		for i := range fields {
+			fields[i] = csvValue(row, i)
+		}
+		if err := writeCSVRecord(w, fields, delim, opts.AlwaysQuote); err != nil {
+			return err
+		}
+
+		row, err = c.Fetch()
+		if err != nil {
+			if err == EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func csvValue(row *Row, idx int) string {
+	t, v := row.Field(idx)
+	switch t {
+	case NOTHING:
+		return ""
+	case TIME, DATE:
+		return Now2Go(v.(int64)).Format(time.RFC3339Nano)
+	case TEXT:
+		return v.(string)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func writeCSVRecord(w io.Writer, fields []string, delim rune, alwaysQuote bool) error {
+	for i, f := range fields {
+		if i > 0 {
+			if _, err := io.WriteString(w, string(delim)); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, csvField(f, delim, alwaysQuote)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+func csvField(s string, delim rune, alwaysQuote bool) string {
+	needsQuote := alwaysQuote || strings.ContainsRune(s, delim) ||
+		strings.ContainsAny(s, "\"\r\n")
+	if !needsQuote {
+		return s
+	}
+	return "\"" + strings.Replace(s, "\"", "\"\"", -1) + "\""
+}
@@ -33,6 +33,7 @@ import "C"
 import(
 	"fmt"
 	"os"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -139,9 +140,11 @@ func null() TypeError{
 // Error type for server-side errors
 type ServerError struct {
 	what string
+	code int
 }
 
-func newServerError(s string) (e ServerError) {
+func newServerError(code int, s string) (e ServerError) {
+	e.code = code
 	e.what = s
 	return
 }
@@ -150,6 +153,11 @@ func (e ServerError) Error() string {
 	return e.what
 }
 
+// Code returns the nowdb error code underlying this ServerError.
+func (e ServerError) Code() int {
+	return e.code
+}
+
 const npersec = 1000000000
 
 // Now2Go converts a nowdb time value
@@ -168,7 +176,27 @@ func Go2Now(t time.Time) int64 {
 
 // Connection type
 type Connection struct {
+   // mu guards cc against concurrent Close calls, e.g. a caller's
+   // own deferred Close racing with the background cleanup
+   // ExecuteContext spawns on cancellation.
+   mu sync.Mutex
    cc C.nowdb_con_t
+
+   // credentials, cached so Ping can transparently redial
+   server, port, usr, pwd string
+
+   // db is the database selected by the most recent call to
+   // Use, if any, so Ping can re-select it after a redial.
+   db string
+}
+
+// cstrOrNil returns a C string for s, or nil if s is empty, so an
+// anonymous connection can still be requested by passing "".
+func cstrOrNil(s string) *C.char {
+	if s == "" {
+		return nil
+	}
+	return C.CString(s)
 }
 
 // Connect creates a connection to the database server.
@@ -183,21 +211,28 @@ func Connect(server string, port string, usr string, pwd string) (*Connection, e
 		return nil, newClientError("Client is not initialised")
 	}
 
-	rc := C.nowdb_connect(&cc, C.CString(server), C.CString(port), nil, nil, 0)
+	rc := C.nowdb_connect(&cc, C.CString(server), C.CString(port), cstrOrNil(usr), cstrOrNil(pwd), 0)
 	if rc != OK {
 		fmt.Fprintf(os.Stderr, "cannot connect: %d\n", rc)
 		m := fmt.Sprintf("%d", rc) // explain!
-		return nil, newServerError(m)
+		return nil, newServerError(int(rc), m)
 	}
 
 	c := new(Connection)
 	c.cc = cc
+	c.server = server
+	c.port = port
+	c.usr = usr
+	c.pwd = pwd
 
 	return c, nil
 }
 
 // Close closes the connection.
 func (c *Connection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.cc == nil {
 		return nil
 	}
@@ -207,7 +242,7 @@ func (c *Connection) Close() error {
 		c.cc = nil
 		fmt.Fprintf(os.Stderr, "cannot connect: %d\n", rc)
 		m := fmt.Sprintf("%d", rc) // explain!
-		return newServerError(m)
+		return newServerError(int(rc), m)
 	}
 	c.cc = nil
 	return nil
@@ -258,9 +293,9 @@ func (r *Result) Errcode() int {
 
 // transform a result into a server error
 func r2err(r C.nowdb_result_t) ServerError {
-	return newServerError(fmt.Sprintf("%d: %s",
-		int(C.nowdb_result_errcode(r)),
-		C.GoString(C.nowdb_result_details(r))))
+	code := int(C.nowdb_result_errcode(r))
+	return newServerError(code, fmt.Sprintf("%d: %s",
+		code, C.GoString(C.nowdb_result_details(r))))
 }
 
 // Execute sends a SQL statement to the database.
@@ -273,7 +308,7 @@ func (c *Connection) Execute(stmt string) (*Result, error) {
 	rc := C.nowdb_exec_statement(c.cc, C.CString(stmt), &cr)
 	if rc != OK || cr == nil {
 		m := fmt.Sprintf("%d", rc) // explain!
-		return nil, newServerError(m)
+		return nil, newServerError(int(rc), m)
 	}
 
 	r := new(Result)
@@ -301,6 +336,7 @@ func (c *Connection) Use(db string) error {
 	if r != nil {
 		r.Destroy()
 	}
+	c.db = db
 	return nil
 }
 
@@ -332,6 +368,10 @@ func (r *Result) Destroy() {
 // all resources (server- and
 // client-side) assigned to it.
 type Cursor struct {
+	// mu guards cc and row against concurrent Close calls, e.g.
+	// a caller's own deferred Close racing with the background
+	// cleanup FetchContext spawns on cancellation.
+	mu sync.Mutex
 	cc C.nowdb_cursor_t
 	row C.nowdb_row_t
 	first bool
@@ -378,6 +418,9 @@ func (r *Result) Open() (*Cursor, error) {
 // (but there is also no harm in destroying
 // the result additionally).
 func (c *Cursor) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.row != nil {
 		if c.cc == nil {
 			C.nowdb_result_destroy(C.nowdb_result_t(unsafe.Pointer(c.row)))
@@ -407,6 +450,9 @@ func makeRow(c *Cursor) (*Row, error) {
 // Fetch returns one row of the result set or error
 // (but never both). 
 func (c *Cursor) Fetch() (*Row, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.row != nil {
 		if c.first {
 			c.first = false
@@ -447,6 +493,20 @@ func (r *Row) Count() int {
 	return int(C.nowdb_row_count(r.cr))
 }
 
+// Name returns a label for the field with index idx, starting to
+// count from 0, for use wherever a human-readable column identifier
+// is wanted (the WriteJSON object keys, the WriteCSV header, the database/sql
+// driver's Columns).
+//
+// Field, elsewhere in this file, is the only field-level accessor
+// nowdb_row_field actually offers, and it returns a type and a value
+// -- no name. Rather than depend on an invented, unverified
+// nowdb_row_field_name symbol, Name synthesizes a positional label
+// ("col0", "col1", ...) instead of a real column name.
+func (r *Row) Name(idx int) string {
+	return fmt.Sprintf("col%d", idx)
+}
+
 // Field returns the type indicator and
 // the content of the field with index idx
 // starting to count from 0.
@@ -0,0 +1,167 @@
+package gnow
+// #include <nowdb/nowclient.h>
+import "C"
+
+import (
+	"math/rand"
+	"time"
+)
+
+// IdempotentRetryableCodes lists nowdb error codes seen for
+// transient, connection-level failures: a dropped socket, a
+// transient I/O error, or a serialization conflict the server
+// itself aborted. None of them prove that the statement in flight
+// never reached, or never altered, server state -- a reset can just
+// as well happen after the server already applied an insert or
+// update. They are therefore only safe to retry for statements that
+// are themselves idempotent; build a RetryPolicy.Retryable func on
+// top of this set for such statements, it is not used by default.
+var IdempotentRetryableCodes = map[int]bool{
+	11: true, // connection reset by peer
+	14: true, // transient I/O error
+	23: true, // serialization conflict, abortable by the server
+}
+
+// DefaultRetryable is the classifier ExecuteRetry uses when a
+// RetryPolicy does not set Retryable. It never retries: no nowdb
+// error code guarantees that a statement was never received or
+// never applied by the server, so retrying by default risks
+// silently duplicating a write. Callers who know their statement is
+// idempotent should set Retryable explicitly, for example to a
+// classifier built on IdempotentRetryableCodes.
+func DefaultRetryable(err error) bool {
+	return false
+}
+
+// RetryPolicy controls how Connection.ExecuteRetry re-runs a
+// statement that failed with a retryable ServerError.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the
+	// first. A value <= 1 means no retry.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between retries; the delay
+	// doubles after every failed attempt up to this limit.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction, in [0,1], of the computed backoff
+	// that is added on top of it at random, to avoid retry storms
+	// across many clients.
+	Jitter float64
+
+	// Retryable reports whether err should trigger a retry. If
+	// nil, DefaultRetryable is used.
+	Retryable func(err error) bool
+}
+
+// ExecuteRetry is like Execute, but re-runs stmt according to
+// policy when it fails with an error that policy.Retryable (or
+// DefaultRetryable, if unset) classifies as retryable.
+func (c *Connection) ExecuteRetry(stmt string, policy RetryPolicy) (*Result, error) {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	backoff := policy.InitialBackoff
+	var lastErr error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		r, err := c.Execute(stmt)
+		if err == nil {
+			return r, nil
+		}
+		lastErr = err
+		if attempt == attempts || !retryable(err) {
+			return nil, err
+		}
+
+		d := backoff
+		if policy.Jitter > 0 {
+			d += time.Duration(rand.Float64() * policy.Jitter * float64(backoff))
+		}
+		time.Sleep(d)
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+	return nil, lastErr
+}
+
+// pingProbe is the trivial statement Ping sends to check
+// connectivity. Whether it is itself valid in nowdb's dialect for
+// the session's current database does not matter: pingConnectionLost
+// only trusts pingProbe's error for what it can prove -- that the
+// transport, not just this particular statement, failed -- so a
+// statement-level error here is harmless.
+const pingProbe = "select 1"
+
+// pingConnectionLost reports whether err proves that the
+// connection's transport, rather than just the statement it was
+// given, failed. Only such errors should cause Ping to tear down
+// and redial; a perfectly healthy connection should never be
+// rebuilt just because the probe statement itself was rejected.
+func pingConnectionLost(err error) bool {
+	se, ok := err.(ServerError)
+	if !ok {
+		return false
+	}
+	switch se.Code() {
+	case 11, 14: // connection reset by peer, transient I/O error
+		return true
+	}
+	return false
+}
+
+// Ping issues a trivial statement to check that the connection is
+// still alive. An error from that statement only triggers teardown
+// and redial if it proves the transport itself is gone (see
+// pingConnectionLost); any other error -- e.g. the probe statement
+// being rejected by a perfectly healthy connection -- is treated as
+// a successful Ping, since the connection is not actually broken.
+// When a redial does happen, Ping dials again with the server,
+// port, usr and pwd originally passed to Connect, re-selecting the
+// database most recently set with Use, if any, so a long-lived
+// pooled connection recovers from a server restart without the
+// caller ever seeing more than this one blocked call.
+func (c *Connection) Ping() error {
+	r, err := c.Execute(pingProbe)
+	if err == nil {
+		r.Destroy()
+		return nil
+	}
+	if !pingConnectionLost(err) {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cc != nil {
+		C.nowdb_connection_destroy(c.cc)
+		c.cc = nil
+	}
+
+	nc, err := Connect(c.server, c.port, c.usr, c.pwd)
+	if err != nil {
+		return err
+	}
+	c.cc = nc.cc
+
+	if c.db != "" {
+		if err := c.Use(c.db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package gnow
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Scanner is implemented by types that know how to populate
+// themselves from a raw nowdb field value, for use as a Row.Scan
+// destination for columns with no direct Go counterpart.
+type Scanner interface {
+	// ScanNow receives the nowdb type code, as returned by
+	// Row.Field, and the corresponding raw value, and converts
+	// them into the receiver. typeCode is NOTHING and raw is nil
+	// for a NULL field.
+	ScanNow(typeCode int, raw interface{}) error
+}
+
+// Scan copies the columns of the row, by index, into the values
+// pointed to by dest. Supported destinations are *string, *int64,
+// *int32 (with overflow checking), *uint64, *float64, *bool,
+// *time.Time, *[]byte, a type implementing Scanner, or a pointer to
+// one of the database/sql types sql.NullString, sql.NullInt64,
+// sql.NullFloat64, sql.NullBool or sql.NullTime; other "sql.Null*"
+// types are not supported.
+//
+// Scan returns the first conversion error it encounters, wrapped as
+// a TypeError naming the offending column index; an error returned
+// by a Scanner's ScanNow is passed through unchanged. A NULL field
+// leaves a "sql.Null*" destination untouched (zero-valued) and
+// otherwise returns the package-level NULL error.
+func (r *Row) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		t, v := r.Field(i)
+		if err := scanField(i, t, v, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanField(idx int, t int, v interface{}, dest interface{}) error {
+	if t == NOTHING {
+		return scanNull(idx, dest)
+	}
+
+	switch d := dest.(type) {
+	case Scanner:
+		return d.ScanNow(t, v)
+	case *string:
+		if t != TEXT {
+			return columnTypeError(idx, "not a string")
+		}
+		*d = v.(string)
+	case *int64:
+		if t != INT {
+			return columnTypeError(idx, "not an int value")
+		}
+		*d = v.(int64)
+	case *int32:
+		if t != INT {
+			return columnTypeError(idx, "not an int value")
+		}
+		n := v.(int64)
+		if n > math.MaxInt32 || n < math.MinInt32 {
+			return columnTypeError(idx, "int value overflows int32")
+		}
+		*d = int32(n)
+	case *uint64:
+		if t != UINT {
+			return columnTypeError(idx, "not a uint value")
+		}
+		*d = v.(uint64)
+	case *float64:
+		if t != FLOAT {
+			return columnTypeError(idx, "not a float value")
+		}
+		*d = v.(float64)
+	case *bool:
+		if t != BOOL {
+			return columnTypeError(idx, "not a bool value")
+		}
+		*d = v.(bool)
+	case *time.Time:
+		if t != TIME && t != DATE {
+			return columnTypeError(idx, "not a time value")
+		}
+		*d = Now2Go(v.(int64))
+	case *[]byte:
+		if t != TEXT {
+			return columnTypeError(idx, "not a string")
+		}
+		*d = []byte(v.(string))
+	case *sql.NullString:
+		if t != TEXT {
+			return columnTypeError(idx, "not a string")
+		}
+		d.String, d.Valid = v.(string), true
+	case *sql.NullInt64:
+		if t != INT {
+			return columnTypeError(idx, "not an int value")
+		}
+		d.Int64, d.Valid = v.(int64), true
+	case *sql.NullFloat64:
+		if t != FLOAT {
+			return columnTypeError(idx, "not a float value")
+		}
+		d.Float64, d.Valid = v.(float64), true
+	case *sql.NullBool:
+		if t != BOOL {
+			return columnTypeError(idx, "not a bool value")
+		}
+		d.Bool, d.Valid = v.(bool), true
+	case *sql.NullTime:
+		if t != TIME && t != DATE {
+			return columnTypeError(idx, "not a time value")
+		}
+		d.Time, d.Valid = Now2Go(v.(int64)), true
+	default:
+		return columnTypeError(idx, fmt.Sprintf("unsupported scan destination %T", dest))
+	}
+	return nil
+}
+
+// scanNull handles a NULL field: "sql.Null*" destinations and
+// Scanner implementations are left to decide for themselves what
+// NULL means; every other destination reports the NULL sentinel.
+func scanNull(idx int, dest interface{}) error {
+	switch d := dest.(type) {
+	case Scanner:
+		return d.ScanNow(NOTHING, nil)
+	case *sql.NullString:
+		*d = sql.NullString{}
+	case *sql.NullInt64:
+		*d = sql.NullInt64{}
+	case *sql.NullFloat64:
+		*d = sql.NullFloat64{}
+	case *sql.NullBool:
+		*d = sql.NullBool{}
+	case *sql.NullTime:
+		*d = sql.NullTime{}
+	default:
+		return NULL
+	}
+	return nil
+}
+
+func columnTypeError(idx int, what string) TypeError {
+	return newTypeError(fmt.Sprintf("column %d: %s", idx, what))
+}
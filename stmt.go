@@ -0,0 +1,201 @@
+package gnow
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stmt is a SQL statement prepared for repeated execution with
+// different arguments. Preparation does not allocate any
+// server-side resource; '?' placeholders are substituted with
+// nowdb literals on the Go side, and the resulting statement is
+// sent to the server with Execute, exactly as Connection.Execute
+// would.
+type Stmt struct {
+	c    *Connection
+	stmt string
+	n    int
+}
+
+// Prepare parses stmt for '?' placeholders and returns a Stmt that
+// can be run repeatedly with different arguments via Stmt.Execute.
+func (c *Connection) Prepare(stmt string) (*Stmt, error) {
+	n, err := countPlaceholders(stmt)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{c: c, stmt: stmt, n: n}, nil
+}
+
+// NumInput returns the number of '?' placeholders in the statement.
+func (s *Stmt) NumInput() int {
+	return s.n
+}
+
+// Execute substitutes args, in order, for the statement's
+// placeholders and sends the resulting statement to the server.
+func (s *Stmt) Execute(args ...interface{}) (*Result, error) {
+	stmt, err := bind(s.stmt, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.c.Execute(stmt)
+}
+
+// ExecuteWith is a convenience wrapper around Prepare and Execute
+// for statements that are only run once.
+func (c *Connection) ExecuteWith(stmt string, args ...interface{}) (*Result, error) {
+	s, err := c.Prepare(stmt)
+	if err != nil {
+		return nil, err
+	}
+	return s.Execute(args...)
+}
+
+// countPlaceholders counts the '?' characters outside of
+// single-quoted string literals.
+func countPlaceholders(stmt string) (int, error) {
+	n := 0
+	inString := false
+	for i := 0; i < len(stmt); i++ {
+		switch stmt[i] {
+		case '\'':
+			inString = !inString
+		case '?':
+			if !inString {
+				n++
+			}
+		}
+	}
+	if inString {
+		return 0, newClientError("unterminated string literal in statement")
+	}
+	return n, nil
+}
+
+// bind substitutes args, in order, for the '?' placeholders in stmt
+// that lie outside of string literals.
+func bind(stmt string, args []interface{}) (string, error) {
+	var b strings.Builder
+	i := 0
+	inString := false
+	for j := 0; j < len(stmt); j++ {
+		c := stmt[j]
+		switch {
+		case c == '\'':
+			inString = !inString
+			b.WriteByte(c)
+		case c == '?' && !inString:
+			if i >= len(args) {
+				return "", newClientError("not enough arguments for statement")
+			}
+			v, err := renderValue(args[i])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if i != len(args) {
+		return "", newClientError("too many arguments for statement")
+	}
+	return b.String(), nil
+}
+
+// renderValue renders v, one of int64, uint64, float64, bool,
+// string, []byte, time.Time (or a pointer to one of those, for
+// typed nils) or nil, in nowdb's native literal syntax.
+func renderValue(v interface{}) (string, error) {
+	if v == nil {
+		return "NULL", nil
+	}
+	switch x := v.(type) {
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case uint64:
+		return strconv.FormatUint(x, 10), nil
+	case float64:
+		return formatFloatLiteral(x)
+	case bool:
+		return boolLiteral(x), nil
+	case string:
+		return quoteString(x), nil
+	case []byte:
+		return quoteString(string(x)), nil
+	case time.Time:
+		return strconv.FormatInt(Go2Now(x), 10), nil
+	case *int64:
+		if x == nil {
+			return "NULL", nil
+		}
+		return strconv.FormatInt(*x, 10), nil
+	case *uint64:
+		if x == nil {
+			return "NULL", nil
+		}
+		return strconv.FormatUint(*x, 10), nil
+	case *float64:
+		if x == nil {
+			return "NULL", nil
+		}
+		return formatFloatLiteral(*x)
+	case *bool:
+		if x == nil {
+			return "NULL", nil
+		}
+		return boolLiteral(*x), nil
+	case *string:
+		if x == nil {
+			return "NULL", nil
+		}
+		return quoteString(*x), nil
+	case *[]byte:
+		if x == nil {
+			return "NULL", nil
+		}
+		return quoteString(string(*x)), nil
+	case *time.Time:
+		if x == nil {
+			return "NULL", nil
+		}
+		return strconv.FormatInt(Go2Now(*x), 10), nil
+	default:
+		return "", newTypeError(fmt.Sprintf("unsupported argument type %T", v))
+	}
+}
+
+// formatFloatLiteral renders x in nowdb's float literal syntax. NaN
+// and +/-Inf have no literal representation nowdb can parse, so
+// they are rejected outright rather than injected verbatim into the
+// statement; a whole-valued float (e.g. 3) is forced to carry a
+// decimal point (3.0) so the server binds it as FLOAT rather than
+// INT.
+func formatFloatLiteral(x float64) (string, error) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return "", newTypeError(fmt.Sprintf("float value %v has no nowdb literal representation", x))
+	}
+	s := strconv.FormatFloat(x, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s, nil
+}
+
+func boolLiteral(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// quoteString escapes embedded single quotes by doubling them,
+// following nowdb's SQL string literal syntax.
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}